@@ -0,0 +1,111 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ArtifactBackend stores funcbench run artifacts (raw benchstat output,
+// []BenchCmp history JSON) so later runs can look up, link and show past
+// results for the same base branch and benchFunc regex.
+type ArtifactBackend interface {
+	// Upload stores r under name and returns a URL it can be retrieved
+	// from.
+	Upload(ctx context.Context, name string, r io.Reader) (url string, err error)
+	// List returns the names of all artifacts stored under prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Load retrieves the artifact previously stored under name.
+	Load(ctx context.Context, name string) (io.ReadCloser, error)
+	// URL returns the retrieval URL for name without fetching it.
+	URL(name string) string
+}
+
+// NewArtifactBackend constructs the ArtifactBackend selected by backend
+// ("fs", "gcs" or "s3"). bucket is the GCS/S3 bucket name, or the root
+// directory for "fs"; prefix namespaces artifacts underneath it (e.g. by
+// repo).
+func NewArtifactBackend(ctx context.Context, backend, bucket, prefix string) (ArtifactBackend, error) {
+	switch backend {
+	case "", "fs":
+		return newFSBackend(bucket, prefix)
+	case "gcs":
+		return newGCSBackend(ctx, bucket, prefix)
+	case "s3":
+		return newS3Backend(bucket, prefix)
+	default:
+		return nil, fmt.Errorf("unknown --artifacts-backend %q", backend)
+	}
+}
+
+// fsBackend is the ArtifactBackend for a local directory.
+type fsBackend struct {
+	root string
+}
+
+func newFSBackend(root, prefix string) (*fsBackend, error) {
+	dir := filepath.Join(root, prefix)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "creating fs artifacts dir")
+	}
+	return &fsBackend{root: dir}, nil
+}
+
+func (b *fsBackend) Upload(_ context.Context, name string, r io.Reader) (string, error) {
+	path := filepath.Join(b.root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", errors.Wrap(err, "creating fs artifact dir")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", errors.Wrap(err, "creating artifact file")
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return "", errors.Wrap(err, "writing artifact file")
+	}
+	return b.URL(name), nil
+}
+
+func (b *fsBackend) List(_ context.Context, prefix string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(b.root, prefix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "listing fs artifacts dir")
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, filepath.Join(prefix, e.Name()))
+		}
+	}
+	return names, nil
+}
+
+func (b *fsBackend) Load(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(b.root, name))
+}
+
+func (b *fsBackend) URL(name string) string {
+	return "file://" + filepath.Join(b.root, name)
+}
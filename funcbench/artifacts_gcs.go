@@ -0,0 +1,81 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// gcsBackend is the ArtifactBackend for a Google Cloud Storage bucket.
+type gcsBackend struct {
+	bucketName string
+	bucket     *storage.BucketHandle
+	prefix     string
+}
+
+func newGCSBackend(ctx context.Context, bucket, prefix string) (*gcsBackend, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating gcs client")
+	}
+	return &gcsBackend{bucketName: bucket, bucket: client.Bucket(bucket), prefix: prefix}, nil
+}
+
+func (b *gcsBackend) object(name string) *storage.ObjectHandle {
+	return b.bucket.Object(path.Join(b.prefix, name))
+}
+
+func (b *gcsBackend) Upload(ctx context.Context, name string, r io.Reader) (string, error) {
+	w := b.object(name).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return "", errors.Wrap(err, "writing gcs object")
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, "closing gcs object")
+	}
+	return b.URL(name), nil
+}
+
+func (b *gcsBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: path.Join(b.prefix, prefix) + "/"})
+
+	var names []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing gcs objects")
+		}
+		names = append(names, strings.TrimPrefix(attrs.Name, b.prefix+"/"))
+	}
+	return names, nil
+}
+
+func (b *gcsBackend) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.object(name).NewReader(ctx)
+}
+
+func (b *gcsBackend) URL(name string) string {
+	return fmt.Sprintf("gs://%s/%s", b.bucketName, path.Join(b.prefix, name))
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+// s3Backend is the ArtifactBackend for an AWS S3 bucket.
+type s3Backend struct {
+	bucket string
+	prefix string
+
+	sess *session.Session
+}
+
+func newS3Backend(bucket, prefix string) (*s3Backend, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating s3 session")
+	}
+	return &s3Backend{bucket: bucket, prefix: prefix, sess: sess}, nil
+}
+
+func (b *s3Backend) key(name string) string {
+	return path.Join(b.prefix, name)
+}
+
+func (b *s3Backend) Upload(ctx context.Context, name string, r io.Reader) (string, error) {
+	uploader := s3manager.NewUploader(b.sess)
+	if _, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(name)),
+		Body:   r,
+	}); err != nil {
+		return "", errors.Wrap(err, "uploading s3 object")
+	}
+	return b.URL(name), nil
+}
+
+func (b *s3Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	client := s3.New(b.sess)
+	keyPrefix := b.key(prefix) + "/"
+
+	var names []string
+	err := client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(keyPrefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.StringValue(obj.Key), b.prefix+"/"))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing s3 objects")
+	}
+	return names, nil
+}
+
+func (b *s3Backend) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	client := s3.New(b.sess)
+	out, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(name)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting s3 object")
+	}
+	return out.Body, nil
+}
+
+func (b *s3Backend) URL(name string) string {
+	return fmt.Sprintf("s3://%s/%s", b.bucket, b.key(name))
+}
@@ -15,18 +15,17 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/google/go-github/v29/github"
 	"github.com/pkg/errors"
-	"golang.org/x/oauth2"
 	"gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/config"
-	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 type Environment interface {
@@ -36,6 +35,12 @@ type Environment interface {
 	PostErr(err string) error
 	PostResults(cmps []BenchCmp) error
 
+	// PublishArtifact uploads r under name to the environment's configured
+	// artifacts backend and returns a URL it can be retrieved from.
+	// Environments without a backend configured (e.g. Local) are a noop
+	// and return ("", nil).
+	PublishArtifact(ctx context.Context, name string, r io.Reader) (string, error)
+
 	Repo() *git.Repository
 }
 
@@ -70,6 +75,8 @@ func newLocalEnv(e environment) (Environment, error) {
 
 func (l *Local) PostErr(string) error { return nil } // Noop. We will see error anyway.
 
+func (l *Local) PublishArtifact(context.Context, string, io.Reader) (string, error) { return "", nil } // Noop.
+
 // formatNs formats ns measurements to expose a useful amount of
 // precision. It mirrors the ns precision logic of testing.B.
 func formatNs(ns float64) string {
@@ -92,102 +99,112 @@ func (l *Local) PostResults(cmps []BenchCmp) error {
 func (l *Local) Repo() *git.Repository { return l.repo }
 
 // TODO: Add unit test(!).
-type GitHub struct {
+// Remote is the Environment used when benchmarking a PR/MR hosted on a
+// remote SCM (GitHub, GitLab or Gitea). All SCM-specific behavior (cloning
+// the right ref, posting status back) is delegated to an SCMProvider so
+// that funcbench itself stays host-agnostic.
+type Remote struct {
 	environment
 
 	repo    *git.Repository
-	client  *gitHubClient
+	scm     SCMProvider
+	backend ArtifactBackend
 	logLink string
 }
 
-func newGitHubEnv(ctx context.Context, e environment, gc *gitHubClient, workspace string) (Environment, error) {
-	r, err := git.PlainCloneContext(ctx, fmt.Sprintf("%s/%s", workspace, gc.repo), false, &git.CloneOptions{
-		URL:      fmt.Sprintf("https://github.com/%s/%s.git", gc.owner, gc.repo),
-		Progress: os.Stdout,
-		Depth:    1,
-	})
+func newRemoteEnv(ctx context.Context, e environment, scm SCMProvider, backend ArtifactBackend, workspace, repoName string) (Environment, error) {
+	r, err := scm.CloneAndCheckoutPR(ctx, workspace)
 	if err != nil {
-		return nil, errors.Wrap(err, "git clone")
+		return nil, err
 	}
 
-	if err := os.Chdir(filepath.Join(workspace, gc.repo)); err != nil {
-		return nil, errors.Wrapf(err, "changing to %s/%s dir", workspace, gc.repo)
+	if err := os.Chdir(filepath.Join(workspace, repoName)); err != nil {
+		return nil, errors.Wrapf(err, "changing to %s/%s dir", workspace, repoName)
 	}
 
-	g := &GitHub{
+	e.logger.Println("Benchmarking versus:", e.compareTarget)
+	e.logger.Println("Benchmark func regex:", e.benchFunc)
+	return &Remote{
 		environment: e,
 		repo:        r,
-		client:      gc,
-	}
+		scm:         scm,
+		backend:     backend,
+	}, nil
+}
 
-	wt, err := g.repo.Worktree()
-	if err != nil {
-		return nil, err
-	}
+func (g *Remote) Repo() *git.Repository { return g.repo }
 
-	if err := r.FetchContext(ctx, &git.FetchOptions{
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(fmt.Sprintf("+refs/pull/%d/head:refs/heads/pullrequest", gc.prNumber)),
-		},
-		Progress: os.Stdout,
-	}); err != nil && err != git.NoErrAlreadyUpToDate {
-		return nil, errors.Wrap(err, "fetch to pull request branch failed")
+func (g *Remote) PostErr(err string) error {
+	if err := g.scm.PostErr(fmt.Sprintf("%v. Logs: %v", err, g.logLink)); err != nil {
+		return errors.Wrap(err, "posting err")
 	}
+	return nil
+}
 
-	if err = wt.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.NewBranchReferenceName("pullrequest"),
-	}); err != nil {
-		return nil, errors.Wrap(err, "switch to pull request branch failed")
+func (g *Remote) PublishArtifact(ctx context.Context, name string, r io.Reader) (string, error) {
+	if g.backend == nil {
+		return "", nil
 	}
-
-	e.logger.Println("[GitHub Mode]", gc.owner, ":", gc.repo)
-	e.logger.Println("Benchmarking PR -", gc.prNumber, "versus:", e.compareTarget)
-	e.logger.Println("Benchmark func regex:", e.benchFunc)
-	return g, nil
+	return g.backend.Upload(ctx, name, r)
 }
 
-func (g *GitHub) Repo() *git.Repository { return g.repo }
+func (g *Remote) PostResults(cmps []BenchCmp) error {
+	b := bytes.Buffer{}
+	Render(&b, cmps, false, false, g.compareTarget)
+	rawTable := b.String()
 
-type gitHubClient struct {
-	owner    string
-	repo     string
-	prNumber int
-	client   *github.Client
+	comment := formatCommentToMD(rawTable)
+	if g.backend != nil {
+		comment += g.publishResultArtifacts(cmps, rawTable)
+	}
+	return g.scm.PostComment(comment)
 }
 
-func newGitHubClient(ctx context.Context, owner, repo string, prNumber int) (*gitHubClient, error) {
-	ghToken, ok := os.LookupEnv("GITHUB_TOKEN")
-	if !ok {
-		return nil, fmt.Errorf("GITHUB_TOKEN missing")
+// publishResultArtifacts uploads this run's raw and JSON artifacts via
+// PublishArtifact and renders a markdown section linking the previous run
+// for this base branch plus the stored results of up to historyDepth prior
+// runs. Those prior results are shown as they were posted; no delta between
+// this run and them is computed. Publishing or history-lookup failures are
+// logged but never block posting the results comment.
+func (g *Remote) publishResultArtifacts(cmps []BenchCmp, rawTable string) string {
+	ctx := context.Background()
+	prefix := historyKey(g.compareTarget, g.benchFunc)
+
+	var out strings.Builder
+
+	names, err := g.backend.List(ctx, prefix)
+	if err != nil {
+		g.logger.Println("listing artifact history failed:", err)
+		names = nil
 	}
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})
-	tc := oauth2.NewClient(ctx, ts)
-	c := gitHubClient{
-		client:   github.NewClient(tc),
-		owner:    owner,
-		repo:     repo,
-		prNumber: prNumber,
+
+	if prevRaw := latestRawRun(names); prevRaw != "" {
+		fmt.Fprintf(&out, "\n\n[Previous run vs `%s`](%s)\n", g.compareTarget, g.backend.URL(prevRaw))
 	}
-	return &c, nil
-}
 
-func (c *gitHubClient) postComment(comment string) error {
-	issueComment := &github.IssueComment{Body: github.String(comment)}
-	_, _, err := c.client.Issues.CreateComment(context.Background(), c.owner, c.repo, c.prNumber, issueComment)
-	return err
-}
+	if history, err := loadHistory(ctx, g.backend, names); err != nil {
+		g.logger.Println("loading benchmark history failed:", err)
+	} else {
+		out.WriteString(renderHistorySection(history, g.compareTarget))
+	}
 
-func (g *GitHub) PostErr(err string) error {
-	if err := g.client.postComment(fmt.Sprintf("%v. Logs: %v", err, g.logLink)); err != nil {
-		return errors.Wrap(err, "posting err")
+	ts := time.Now().UTC().Format("20060102T150405Z")
+	if rawURL, err := g.PublishArtifact(ctx, fmt.Sprintf("%s/%s.txt", prefix, ts), strings.NewReader(rawTable)); err != nil {
+		g.logger.Println("publishing raw artifact failed:", err)
+	} else {
+		fmt.Fprintf(&out, "\n[Raw results](%s)\n", rawURL)
 	}
-	return nil
-}
 
-func (g *GitHub) PostResults(cmps []BenchCmp) error {
-	b := bytes.Buffer{}
-	Render(&b, cmps, false, false, g.compareTarget)
-	return g.client.postComment(formatCommentToMD(b.String()))
+	cmpJSON, err := json.Marshal(cmps)
+	if err != nil {
+		g.logger.Println("marshaling results failed:", err)
+		return out.String()
+	}
+	if _, err := g.PublishArtifact(ctx, fmt.Sprintf("%s/%s.json", prefix, ts), bytes.NewReader(cmpJSON)); err != nil {
+		g.logger.Println("publishing history artifact failed:", err)
+	}
+
+	return out.String()
 }
 
 func formatCommentToMD(rawTable string) string {
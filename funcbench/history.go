@@ -0,0 +1,103 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// historyDepth bounds how many prior runs are considered for the
+// delta-vs-history section of a posted comment.
+const historyDepth = 5
+
+// historyKey groups artifacts that are comparable across runs: same base
+// branch and same benchFunc regex.
+func historyKey(compareTarget, benchFunc string) string {
+	r := strings.NewReplacer("/", "_", " ", "_")
+	return fmt.Sprintf("%s-%s", r.Replace(compareTarget), r.Replace(benchFunc))
+}
+
+func filterExt(names []string, ext string) []string {
+	var out []string
+	for _, n := range names {
+		if path.Ext(n) == ext {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// loadHistory loads the up-to-historyDepth most recent []BenchCmp JSON
+// artifacts among names, oldest first.
+func loadHistory(ctx context.Context, backend ArtifactBackend, names []string) ([][]BenchCmp, error) {
+	jsonNames := filterExt(names, ".json")
+	if len(jsonNames) > historyDepth {
+		jsonNames = jsonNames[len(jsonNames)-historyDepth:]
+	}
+
+	var runs [][]BenchCmp
+	for _, n := range jsonNames {
+		rc, err := backend.Load(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var cmps []BenchCmp
+		if err := json.Unmarshal(body, &cmps); err != nil {
+			return nil, err
+		}
+		runs = append(runs, cmps)
+	}
+	return runs, nil
+}
+
+// latestRawRun returns the name of the most recently uploaded raw
+// benchstat-style artifact among names, if any.
+func latestRawRun(names []string) string {
+	rawNames := filterExt(names, ".txt")
+	if len(rawNames) == 0 {
+		return ""
+	}
+	return rawNames[len(rawNames)-1]
+}
+
+// renderHistorySection formats a markdown section summarizing up to
+// historyDepth prior runs for compareTarget, oldest first, most recent last.
+func renderHistorySection(history [][]BenchCmp, compareTarget string) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n### History (last %d run(s) vs `%s`)\n\n", len(history), compareTarget)
+	for i, cmps := range history {
+		var rb bytes.Buffer
+		Render(&rb, cmps, false, false, compareTarget)
+		fmt.Fprintf(&b, "<details><summary>Run %d/%d (%d benchmark(s))</summary>\n\n```\n%s\n```\n\n</details>\n\n",
+			i+1, len(history), len(cmps), rb.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// SCMProvider abstracts away the source-code-hosting-specific parts of
+// funcbench: cloning the branch under test and posting status back to the
+// pull/merge request. This lets funcbench run against repos hosted on
+// GitHub, GitLab or Gitea without the rest of the tool knowing the
+// difference.
+type SCMProvider interface {
+	// CloneAndCheckoutPR clones the repository into workspace and checks out
+	// the ref under test (e.g. a PR or MR head ref).
+	CloneAndCheckoutPR(ctx context.Context, workspace string) (*git.Repository, error)
+	// PostComment posts body as a comment on the PR/MR under test.
+	PostComment(body string) error
+	// PostErr posts errMsg as a comment on the PR/MR under test.
+	PostErr(errMsg string) error
+}
+
+// NewSCMProvider constructs the SCMProvider selected by scm ("github",
+// "gitlab" or "gitea"). owner/repo/number identify the repository and the
+// PR/MR under test; remaining provider-specific configuration (tokens,
+// base URLs) is read from environment variables by each provider.
+func NewSCMProvider(ctx context.Context, scm, owner, repo string, number int) (SCMProvider, error) {
+	switch scm {
+	case "", "github":
+		return newGitHubProvider(ctx, owner, repo, number)
+	case "gitlab":
+		return newGitLabProvider(owner, repo, number)
+	case "gitea":
+		return newGiteaProvider(owner, repo, number)
+	default:
+		return nil, fmt.Errorf("unknown --scm provider %q", scm)
+	}
+}
+
+// cloneAndCheckoutRef is the shared implementation behind every
+// SCMProvider's CloneAndCheckoutPR: it clones cloneURL into
+// workspace/repo, fetches refSpec, and checks the fetched ref out as
+// branch "pullrequest". Providers only differ in the clone URL they
+// build and the refspec they fetch (a PR head ref, an MR head ref, ...).
+func cloneAndCheckoutRef(ctx context.Context, cloneURL, workspace, repo, refSpec string) (*git.Repository, error) {
+	r, err := git.PlainCloneContext(ctx, fmt.Sprintf("%s/%s", workspace, repo), false, &git.CloneOptions{
+		URL:      cloneURL,
+		Progress: os.Stdout,
+		Depth:    1,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "git clone")
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(refSpec)},
+		Progress: os.Stdout,
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, errors.Wrap(err, "fetch to pull request branch failed")
+	}
+
+	if err := wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName("pullrequest"),
+	}); err != nil {
+		return nil, errors.Wrap(err, "switch to pull request branch failed")
+	}
+
+	return r, nil
+}
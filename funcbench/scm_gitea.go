@@ -0,0 +1,76 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+// giteaProvider is the SCMProvider for repos hosted on a Gitea instance,
+// driven by the GITEA_URL and GITEA_TOKEN environment variables. prNumber
+// is the pull request's repo-local index.
+type giteaProvider struct {
+	baseURL  string
+	owner    string
+	repo     string
+	prNumber int
+
+	client *gitea.Client
+}
+
+func newGiteaProvider(owner, repo string, prNumber int) (*giteaProvider, error) {
+	token, ok := os.LookupEnv("GITEA_TOKEN")
+	if !ok {
+		return nil, fmt.Errorf("GITEA_TOKEN missing")
+	}
+	baseURL, ok := os.LookupEnv("GITEA_URL")
+	if !ok {
+		return nil, fmt.Errorf("GITEA_URL missing")
+	}
+
+	client, err := gitea.NewClient(baseURL, gitea.SetToken(token))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating gitea client")
+	}
+
+	return &giteaProvider{
+		baseURL:  baseURL,
+		owner:    owner,
+		repo:     repo,
+		prNumber: prNumber,
+		client:   client,
+	}, nil
+}
+
+func (p *giteaProvider) CloneAndCheckoutPR(ctx context.Context, workspace string) (*git.Repository, error) {
+	cloneURL := fmt.Sprintf("%s/%s/%s.git", p.baseURL, p.owner, p.repo)
+	refSpec := fmt.Sprintf("+refs/pull/%d/head:refs/heads/pullrequest", p.prNumber)
+	return cloneAndCheckoutRef(ctx, cloneURL, workspace, p.repo, refSpec)
+}
+
+func (p *giteaProvider) PostComment(body string) error {
+	_, _, err := p.client.CreateIssueComment(p.owner, p.repo, int64(p.prNumber), gitea.CreateIssueCommentOption{
+		Body: body,
+	})
+	return err
+}
+
+func (p *giteaProvider) PostErr(errMsg string) error {
+	return p.PostComment(errMsg)
+}
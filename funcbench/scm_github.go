@@ -0,0 +1,64 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v29/github"
+	"golang.org/x/oauth2"
+	"gopkg.in/src-d/go-git.v4"
+)
+
+// githubProvider is the SCMProvider for repos hosted on github.com, driven
+// by the GITHUB_TOKEN environment variable.
+type githubProvider struct {
+	owner    string
+	repo     string
+	prNumber int
+
+	client *github.Client
+}
+
+func newGitHubProvider(ctx context.Context, owner, repo string, prNumber int) (*githubProvider, error) {
+	ghToken, ok := os.LookupEnv("GITHUB_TOKEN")
+	if !ok {
+		return nil, fmt.Errorf("GITHUB_TOKEN missing")
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})
+	tc := oauth2.NewClient(ctx, ts)
+	return &githubProvider{
+		owner:    owner,
+		repo:     repo,
+		prNumber: prNumber,
+		client:   github.NewClient(tc),
+	}, nil
+}
+
+func (p *githubProvider) CloneAndCheckoutPR(ctx context.Context, workspace string) (*git.Repository, error) {
+	cloneURL := fmt.Sprintf("https://github.com/%s/%s.git", p.owner, p.repo)
+	refSpec := fmt.Sprintf("+refs/pull/%d/head:refs/heads/pullrequest", p.prNumber)
+	return cloneAndCheckoutRef(ctx, cloneURL, workspace, p.repo, refSpec)
+}
+
+func (p *githubProvider) PostComment(body string) error {
+	issueComment := &github.IssueComment{Body: github.String(body)}
+	_, _, err := p.client.Issues.CreateComment(context.Background(), p.owner, p.repo, p.prNumber, issueComment)
+	return err
+}
+
+func (p *githubProvider) PostErr(errMsg string) error {
+	return p.PostComment(errMsg)
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/xanzy/go-gitlab"
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+// gitlabProvider is the SCMProvider for repos hosted on GitLab (gitlab.com
+// or a self-managed instance), driven by the GITLAB_URL and GITLAB_TOKEN
+// environment variables. mrNumber is the merge request's project-local IID.
+type gitlabProvider struct {
+	baseURL  string
+	owner    string
+	repo     string
+	mrNumber int
+
+	client *gitlab.Client
+}
+
+func newGitLabProvider(owner, repo string, mrNumber int) (*gitlabProvider, error) {
+	token, ok := os.LookupEnv("GITLAB_TOKEN")
+	if !ok {
+		return nil, fmt.Errorf("GITLAB_TOKEN missing")
+	}
+	baseURL := os.Getenv("GITLAB_URL")
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+
+	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(baseURL+"/api/v4"))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating gitlab client")
+	}
+
+	return &gitlabProvider{
+		baseURL:  baseURL,
+		owner:    owner,
+		repo:     repo,
+		mrNumber: mrNumber,
+		client:   client,
+	}, nil
+}
+
+func (p *gitlabProvider) projectID() string {
+	return fmt.Sprintf("%s/%s", p.owner, p.repo)
+}
+
+func (p *gitlabProvider) CloneAndCheckoutPR(ctx context.Context, workspace string) (*git.Repository, error) {
+	cloneURL := fmt.Sprintf("%s/%s/%s.git", p.baseURL, p.owner, p.repo)
+	refSpec := fmt.Sprintf("+refs/merge-requests/%d/head:refs/heads/pullrequest", p.mrNumber)
+	return cloneAndCheckoutRef(ctx, cloneURL, workspace, p.repo, refSpec)
+}
+
+func (p *gitlabProvider) PostComment(body string) error {
+	_, _, err := p.client.Notes.CreateMergeRequestNote(p.projectID(), p.mrNumber, &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.String(body),
+	})
+	return err
+}
+
+func (p *gitlabProvider) PostErr(errMsg string) error {
+	return p.PostComment(errMsg)
+}
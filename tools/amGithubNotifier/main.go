@@ -14,7 +14,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -22,25 +26,160 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v26/github"
 	"github.com/prometheus/alertmanager/notify/webhook"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/oauth2"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
+var (
+	alertsReceivedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "am_github_notifier_alerts_received_total",
+		Help: "Total number of webhook requests received.",
+	})
+	alertsDecodeFailedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "am_github_notifier_alerts_decode_failed_total",
+		Help: "Total number of webhook requests that failed to decode.",
+	})
+	alertsFailedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "am_github_notifier_alerts_failed_total",
+		Help: "Total number of webhook requests whose alerts failed to be processed or posted to GitHub.",
+	})
+	githubRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "am_github_notifier_github_request_duration_seconds",
+		Help:    "Latency of GitHub API calls made by amGithubNotifier.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"op"})
+	githubRateLimitRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "am_github_notifier_github_rate_limit_remaining",
+		Help: "Remaining GitHub API calls in the current rate-limit window, as of the last API call.",
+	})
+	githubRateLimitLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "am_github_notifier_github_rate_limit",
+		Help: "GitHub API rate limit for the current window, as of the last API call.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		alertsReceivedTotal,
+		alertsDecodeFailedTotal,
+		alertsFailedTotal,
+		githubRequestDuration,
+		githubRateLimitRemaining,
+		githubRateLimitLimit,
+	)
+}
+
+// commentMarker wraps a group's fingerprint in a hidden HTML comment so a
+// previously-posted comment for that group can be found again via
+// Issues.ListComments and updated in place instead of piling up duplicates.
+const (
+	commentMarkerPrefix = "<!-- amGithubNotifier: "
+	commentMarkerSuffix = " -->"
+)
+
+func commentMarker(fp string) string {
+	return commentMarkerPrefix + fp + commentMarkerSuffix
+}
+
+// alertTarget identifies the GitHub PR an alert is addressed to. Alertmanager
+// only guarantees that alerts sharing a notification group agree on whatever
+// labels are listed in group_by (typically alertname), not on prNum, so two
+// alerts in the same msg.Alerts can legitimately target different PRs.
+type alertTarget struct {
+	owner string
+	repo  string
+	prNum int
+}
+
+// groupAlertsByTarget partitions alerts by the (owner, repo, prNum) each is
+// individually addressed to via getTargetOwner/getTargetRepo/getTargetPR,
+// preserving the order targets are first seen in alerts.
+func (g ghWebhookReceiver) groupAlertsByTarget(alerts []template.Alert) ([]alertTarget, map[alertTarget][]template.Alert, error) {
+	groups := make(map[alertTarget][]template.Alert)
+	var order []alertTarget
+	for _, a := range alerts {
+		prNum, err := getTargetPR(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		t := alertTarget{owner: g.getTargetOwner(a), repo: g.getTargetRepo(a), prNum: prNum}
+		if _, ok := groups[t]; !ok {
+			order = append(order, t)
+		}
+		groups[t] = append(groups[t], a)
+	}
+	return order, groups, nil
+}
+
+// commonLabels returns the labels shared by every alert in alerts, the same
+// way Alertmanager derives a notification's CommonLabels. It is used to
+// fingerprint a per-target group of alerts instead of the whole message, so
+// the fingerprint doesn't depend on which alert in msg.Alerts happens to be
+// processed first.
+func commonLabels(alerts []template.Alert) template.KV {
+	if len(alerts) == 0 {
+		return nil
+	}
+	common := template.KV{}
+	for k, v := range alerts[0].Labels {
+		common[k] = v
+	}
+	for _, a := range alerts[1:] {
+		for k, v := range common {
+			if a.Labels[k] != v {
+				delete(common, k)
+			}
+		}
+	}
+	return common
+}
+
+// fingerprint returns a stable identifier for the alert group labels
+// belongs to: alertname plus every label except prNum, which only selects
+// the target PR and does not otherwise identify the alert.
+func fingerprint(labels template.KV) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k == "prNum" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, labels[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
 type ghWebhookReceiverConfig struct {
-	authFile     string
-	defaultOwner string
-	defaultRepo  string
-	portNo       string
-	dryRun       bool
+	authFile          string
+	defaultOwner      string
+	defaultRepo       string
+	portNo            string
+	dryRun            bool
+	webhookSecretFile string
+	tlsCert           string
+	tlsKey            string
 }
 
 type ghWebhookReceiver struct {
 	ghClient *github.Client
 	cfg      ghWebhookReceiverConfig
+	// webhookSecret, when non-empty, is the shared secret used to verify the
+	// X-Alertmanager-Signature header on incoming webhook requests.
+	webhookSecret []byte
 }
 
 type ghWebhookHandler struct {
@@ -76,6 +215,9 @@ func main() {
 	app.Flag("repo", "default repo").Required().StringVar(&cfg.defaultRepo)
 	app.Flag("port", "port number to run the server in").Default("8080").StringVar(&cfg.portNo)
 	app.Flag("dryrun", "dry run for github api").BoolVar(&cfg.dryRun)
+	app.Flag("webhook-secret-file", "path to a file containing the shared secret used to verify the X-Alertmanager-Signature header; signature verification is disabled if unset").StringVar(&cfg.webhookSecretFile)
+	app.Flag("tls-cert", "path to a TLS certificate; serves over TLS together with --tls-key").StringVar(&cfg.tlsCert)
+	app.Flag("tls-key", "path to a TLS private key; serves over TLS together with --tls-cert").StringVar(&cfg.tlsKey)
 
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -87,19 +229,53 @@ func main() {
 	serveWebhook(client)
 }
 
+// verifySignature reports whether sigHeader (an "X-Alertmanager-Signature"
+// value of the form "sha256=<hex>") is a valid HMAC-SHA256 of body under
+// secret.
+func verifySignature(secret, body []byte, sigHeader string) bool {
+	const prefix = "sha256="
+	if !strings.HasPrefix(sigHeader, prefix) {
+		return false
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(sigHeader, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
 func (hl ghWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	alertsReceivedTotal.Inc()
+
 	if r.Method != http.MethodPost {
 		log.Printf("unsupported request method: %v: %v", r.Method, r.RemoteAddr)
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("failed to read webhook body")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(hl.client.webhookSecret) > 0 {
+		if !verifySignature(hl.client.webhookSecret, body, r.Header.Get("X-Alertmanager-Signature")) {
+			log.Printf("rejected webhook with invalid signature: %v", r.RemoteAddr)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
 	msg := &webhook.Message{}
 	ctx := r.Context()
 
 	// Decode the webhook request.
-	err := json.NewDecoder(r.Body).Decode(msg)
-	if err != nil {
+	if err := json.Unmarshal(body, msg); err != nil {
+		alertsDecodeFailedTotal.Inc()
 		log.Println("failed to decode webhook data")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -108,6 +284,7 @@ func (hl ghWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Handle the webhook message.
 	log.Printf("handling alert: %v", alertID(msg))
 	if _, err := hl.client.processAlerts(ctx, msg); err != nil {
+		alertsFailedTotal.Inc()
 		log.Printf("failed to handle alert: %v: %v", alertID(msg), err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -118,10 +295,20 @@ func (hl ghWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func newGhWebhookReceiver(cfg ghWebhookReceiverConfig) (*ghWebhookReceiver, error) {
 
+	var secret []byte
+	if cfg.webhookSecretFile != "" {
+		s, err := ioutil.ReadFile(cfg.webhookSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = bytes.TrimSpace(s)
+	}
+
 	if cfg.dryRun {
 		return &ghWebhookReceiver{
-			ghClient: github.NewClient(nil),
-			cfg:      cfg,
+			ghClient:      github.NewClient(nil),
+			cfg:           cfg,
+			webhookSecret: secret,
 		}, nil
 	}
 
@@ -137,52 +324,151 @@ func newGhWebhookReceiver(cfg ghWebhookReceiverConfig) (*ghWebhookReceiver, erro
 	tc := oauth2.NewClient(ctx, ts)
 
 	return &ghWebhookReceiver{
-		ghClient: github.NewClient(tc),
-		cfg:      cfg,
+		ghClient:      github.NewClient(tc),
+		cfg:           cfg,
+		webhookSecret: secret,
 	}, nil
 }
 
-// processAlert formats and posts the alert to github
-func (g ghWebhookReceiver) processAlert(ctx context.Context, alert template.Alert) (string, error) {
-	msgBody, err := formatIssueCommentBody(alert)
-	if err != nil {
-		return "", err
+// formatGroupCommentBody renders every alert in alerts into a single comment
+// body for the group identified by fp: firing alerts first, followed by a
+// "Resolved" section for alerts whose Status is "resolved". The body is
+// prefixed with fp's hidden marker so the comment can be found again later.
+func formatGroupCommentBody(fp string, alerts []template.Alert) (string, error) {
+	var firing, resolved []string
+	for _, a := range alerts {
+		entry, err := formatIssueCommentBody(a)
+		if err != nil {
+			return "", err
+		}
+		if a.Status == "resolved" {
+			resolved = append(resolved, entry)
+		} else {
+			firing = append(firing, entry)
+		}
 	}
-	issueComment := github.IssueComment{Body: &msgBody}
 
-	prNum, err := getTargetPR(alert)
-	if err != nil {
-		return "", err
+	var b strings.Builder
+	fmt.Fprintln(&b, commentMarker(fp))
+	if len(firing) > 0 {
+		fmt.Fprintln(&b, "### Firing")
+		for _, e := range firing {
+			fmt.Fprintln(&b, e)
+		}
+	}
+	if len(resolved) > 0 {
+		fmt.Fprintln(&b, "### Resolved")
+		for _, e := range resolved {
+			fmt.Fprintln(&b, e)
+		}
+	}
+	return b.String(), nil
+}
+
+// observeGitHubCall times a GitHub API call labeled op, records the
+// rate-limit values reported in its response, and returns the call's error.
+func observeGitHubCall(op string, fn func() (*github.Response, error)) error {
+	start := time.Now()
+	resp, err := fn()
+	githubRequestDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if resp != nil {
+		githubRateLimitRemaining.Set(float64(resp.Rate.Remaining))
+		githubRateLimitLimit.Set(float64(resp.Rate.Limit))
+	}
+	return err
+}
+
+// upsertComment creates a new comment carrying fp's marker, or edits the
+// comment already carrying that marker if this group has posted before. It
+// paginates through every comment on the PR looking for the marker, since a
+// busy PR can easily have more comments than a single page.
+func (g ghWebhookReceiver) upsertComment(ctx context.Context, owner, repo string, prNum int, fp, body string) error {
+	marker := commentMarker(fp)
+
+	opt := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
 	}
+	for {
+		var comments []*github.IssueComment
+		var resp *github.Response
+		if err := observeGitHubCall("list_comments", func() (*github.Response, error) {
+			var err error
+			comments, resp, err = g.ghClient.Issues.ListComments(ctx, owner, repo, prNum, opt)
+			return resp, err
+		}); err != nil {
+			return err
+		}
+
+		for _, c := range comments {
+			if c.Body != nil && strings.HasPrefix(*c.Body, marker) {
+				return observeGitHubCall("edit_comment", func() (*github.Response, error) {
+					_, resp, err := g.ghClient.Issues.EditComment(ctx, owner, repo, c.GetID(), &github.IssueComment{Body: &body})
+					return resp, err
+				})
+			}
+		}
 
-	if g.cfg.dryRun {
-		return msgBody, err
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
 	}
-	_, _, err = g.ghClient.Issues.CreateComment(ctx,
-		g.getTargetOwner(alert), g.getTargetRepo(alert), prNum, &issueComment)
 
-	return msgBody, err
+	return observeGitHubCall("create_comment", func() (*github.Response, error) {
+		_, resp, err := g.ghClient.Issues.CreateComment(ctx, owner, repo, prNum, &github.IssueComment{Body: &body})
+		return resp, err
+	})
 }
 
+// processAlerts splits msg.Alerts into one group per (owner, repo, prNum)
+// target and, for each group, collapses its firing and resolved alerts into
+// a single grouped comment on that target PR, updating the group's previous
+// comment in place when one exists instead of posting a new one per alert.
 func (g ghWebhookReceiver) processAlerts(ctx context.Context, msg *webhook.Message) ([]string, error) {
+	if len(msg.Alerts) == 0 {
+		return nil, nil
+	}
 
-	var alertcomments []string
+	targets, groups, err := g.groupAlertsByTarget(msg.Alerts)
+	if err != nil {
+		return nil, err
+	}
 
-	// each alert will have its own comment
-	for _, a := range msg.Alerts {
-		alertcomment, err := g.processAlert(ctx, a)
+	var bodies []string
+	for _, t := range targets {
+		alerts := groups[t]
+		fp := fingerprint(commonLabels(alerts))
+		body, err := formatGroupCommentBody(fp, alerts)
 		if err != nil {
 			return nil, err
 		}
-		alertcomments = append(alertcomments, alertcomment)
+
+		if g.cfg.dryRun {
+			bodies = append(bodies, body)
+			continue
+		}
+
+		if err := g.upsertComment(ctx, t.owner, t.repo, t.prNum, fp, body); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
 	}
-	return alertcomments, nil
+	return bodies, nil
 }
 
 func serveWebhook(client *ghWebhookReceiver) {
 	hl := ghWebhookHandler{client}
-	http.Handle("/hook", hl)
+	mux := http.NewServeMux()
+	mux.Handle("/hook", hl)
+	mux.Handle("/metrics", promhttp.Handler())
+
 	log.Printf("finished setting up gh client. starting amGithubNotifier with %v/%v as defaults",
 		client.cfg.defaultOwner, client.cfg.defaultRepo)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", client.cfg.portNo), nil))
+
+	addr := fmt.Sprintf(":%v", client.cfg.portNo)
+	if client.cfg.tlsCert != "" || client.cfg.tlsKey != "" {
+		log.Fatal(http.ListenAndServeTLS(addr, client.cfg.tlsCert, client.cfg.tlsKey, mux))
+		return
+	}
+	log.Fatal(http.ListenAndServe(addr, mux))
 }